@@ -23,6 +23,16 @@ import (
 
 func use(interface{}) {}
 
+// TestMultiSuffixCompiler asserts against the output of the dbenum
+// secondary generator registered by compilers/main.go. The
+// compilers/protogen package implements the same String() override on
+// google.golang.org/protobuf/compiler/protogen instead of gogo, for
+// go_proto_library users who want to write a dbenum-style generator
+// without a gogo dependency; it emits only String(), none of the
+// parse, metadata or registration code. It has no BUILD target of its
+// own, so it
+// is exercised against a fixture request in its own
+// TestGenerateFile rather than through this compiled-in package.
 func TestMultiSuffixCompiler(t *testing.T) {
 	// just make sure types and generated functions exist
 	v := enum.Enum_BYTES
@@ -36,3 +46,34 @@ func TestMultiSuffixCompiler(t *testing.T) {
 		panic(v.String())
 	}
 }
+
+// TestDBEnumJSONRoundTrip proves the generated MarshalJSON/
+// UnmarshalJSON pair is a drop-in replacement for a plain string
+// column: a dbenum-annotated value serializes as its override string
+// (enum.Enum_BYTES) and an unannotated one as its proto name
+// (enum.Enum_INT32), both parsing back to the original value.
+func TestDBEnumJSONRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		value enum.Enum
+		json  string
+	}{
+		{enum.Enum_BYTES, `"bytes_type"`},
+		{enum.Enum_INT32, `"INT32"`},
+	} {
+		b, err := tc.value.MarshalJSON()
+		if err != nil {
+			t.Fatalf("%v.MarshalJSON() = %v", tc.value, err)
+		}
+		if string(b) != tc.json {
+			t.Fatalf("%v.MarshalJSON() = %s, want %s", tc.value, b, tc.json)
+		}
+
+		var got enum.Enum
+		if err := got.UnmarshalJSON(b); err != nil {
+			t.Fatalf("UnmarshalJSON(%s) = %v", b, err)
+		}
+		if got != tc.value {
+			t.Fatalf("UnmarshalJSON(%s) = %v, want %v", b, got, tc.value)
+		}
+	}
+}
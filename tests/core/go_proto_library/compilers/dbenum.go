@@ -0,0 +1,435 @@
+/* Copyright 2019 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package compilers implements the custom protoc plugins invoked by
+// the go_proto_library multi-suffix compiler test. The dbenum
+// generator overrides String() for any enum that has at least one
+// value annotated with the (dbenum.value) extension, so that
+// generated enums can round-trip through a database or JSON column
+// using an application-chosen string instead of their proto name.
+// Values may additionally carry a structured @dbenum JSON comment,
+// decoded into the runtime dbenum.ValueMeta type and surfaced through
+// a generated Enum_dbmeta map and DBMeta() accessor. Every
+// dbenum-annotated enum also registers an EnumInfo with the dbenum
+// runtime package from its generated init(), so it can be looked up
+// by proto name at runtime, and gets a matching
+// ParseEnum/IsValid/MarshalText/UnmarshalText/MarshalJSON/
+// UnmarshalJSON set so it round-trips through a database or JSON
+// column like a plain string. Two values resolving to the same
+// dbenum string is a compile-time error.
+package compilers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	descriptor "github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	"github.com/gogo/protobuf/protoc-gen-gogo/generator"
+
+	"github.com/bazelbuild/rules_go/tests/core/go_proto_library/dbenum"
+)
+
+// dbenumMarker introduces structured per-value metadata in an enum
+// value's trailing comment, e.g.:
+//
+//	BYTES = 1; // @dbenum {"display":"bytes_type","tags":["binary"]}
+//
+// A value with no marker keeps the plain dbEnumValue() override (or
+// falls back to its proto name) and gets no ValueMeta entry.
+const dbenumMarker = "@dbenum "
+
+var valueMetaKeys = map[string]bool{"display": true, "deprecated": true, "tags": true}
+
+// parseValueMeta extracts and decodes the @dbenum JSON object trailing
+// comment, if any, into the runtime dbenum.ValueMeta type that the
+// generated code will also reference. ok is false when the marker is
+// absent, in which case callers keep their current, non-metadata
+// behavior. strict rejects JSON objects with unrecognized keys.
+func parseValueMeta(comment string, strict bool) (meta dbenum.ValueMeta, ok bool, err error) {
+	idx := strings.Index(comment, dbenumMarker)
+	if idx < 0 {
+		return dbenum.ValueMeta{}, false, nil
+	}
+	raw := strings.TrimSpace(comment[idx+len(dbenumMarker):])
+	if strict {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+			return dbenum.ValueMeta{}, true, fmt.Errorf("invalid @dbenum JSON: %v", err)
+		}
+		for k := range fields {
+			if !valueMetaKeys[k] {
+				return dbenum.ValueMeta{}, true, fmt.Errorf("unknown @dbenum key %q", k)
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(raw), &meta); err != nil {
+		return dbenum.ValueMeta{}, true, fmt.Errorf("invalid @dbenum JSON: %v", err)
+	}
+	return meta, true, nil
+}
+
+// E_DBEnum is the (dbenum.value) extension on EnumValueOptions. When
+// set, it names the string the generated String() method returns for
+// that value instead of the value's proto name.
+var E_DBEnum = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.EnumValueOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         64001,
+	Name:          "dbenum.value",
+	Tag:           "bytes,64001,opt,name=value",
+}
+
+// HasDBEnum reports whether any of the given enum values carry the
+// dbenum override extension.
+func HasDBEnum(values []*descriptor.EnumValueDescriptorProto) bool {
+	for _, v := range values {
+		if dbEnumValue(v) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDBEnumFile reports whether file declares any enum with at least
+// one dbenum-annotated value.
+func HasDBEnumFile(file *descriptor.FileDescriptorProto) bool {
+	for _, enum := range file.EnumType {
+		if HasDBEnum(enum.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func dbEnumValue(v *descriptor.EnumValueDescriptorProto) string {
+	if v.GetOptions() == nil {
+		return ""
+	}
+	ext, err := proto.GetExtension(v.GetOptions(), E_DBEnum)
+	if err != nil {
+		return ""
+	}
+	s, ok := ext.(*string)
+	if !ok || s == nil {
+		return ""
+	}
+	return *s
+}
+
+// dbGenerator is a gogo protoc-gen plugin that overrides String() for
+// every enum with at least one dbenum-annotated value.
+type dbGenerator struct {
+	*generator.Generator
+}
+
+// NewGenerator returns the plugin main invokes as the secondary
+// "_dbenum.pb.go" generation pass.
+func NewGenerator() generator.Plugin {
+	return &dbGenerator{}
+}
+
+func (p *dbGenerator) Name() string { return "dbenum" }
+
+func (p *dbGenerator) Init(g *generator.Generator) { p.Generator = g }
+
+// dbenumRuntimeImportPath is the package every generated
+// "_dbenum.pb.go" file registers its enums into at init().
+const dbenumRuntimeImportPath = "github.com/bazelbuild/rules_go/tests/core/go_proto_library/dbenum"
+
+func (p *dbGenerator) GenerateImports(file *generator.FileDescriptor) {
+	if !HasDBEnumFile(file.FileDescriptorProto) {
+		return
+	}
+	p.P(`import "encoding/json"`)
+	p.P(`import "fmt"`)
+	p.P(`import dbenum "`, dbenumRuntimeImportPath, `"`)
+	p.P(`import "google.golang.org/protobuf/reflect/protoreflect"`)
+}
+
+func (p *dbGenerator) Generate(file *generator.FileDescriptor) {
+	strict := p.Param["dbenum_strict"] == "true"
+	for enumIdx, enum := range file.EnumType {
+		if !HasDBEnum(enum.Value) {
+			continue
+		}
+		p.checkAmbiguity(enum)
+		metas := p.valueMetas(file, enumIdx, enum, strict)
+		p.generateString(enum)
+		p.generateParse(enum)
+		if len(metas) > 0 {
+			p.generateMeta(enum, metas)
+		}
+		p.generateRegister(file, enum)
+	}
+}
+
+// checkAmbiguity fails generation if two values of enum would resolve
+// to the same dbenum string, since ParseEnum could then never recover
+// the original value from its serialized form.
+func (p *dbGenerator) checkAmbiguity(enum *descriptor.EnumDescriptorProto) {
+	if err := dbenumAmbiguity(enum); err != nil {
+		p.Fail(err.Error())
+	}
+}
+
+// dbenumAmbiguity reports the first pair of values in enum that
+// resolve to the same dbenum string, if any. It is separate from
+// checkAmbiguity so it can be unit tested without depending on a live
+// *generator.Generator, whose Fail exits the process.
+func dbenumAmbiguity(enum *descriptor.EnumDescriptorProto) error {
+	seen := make(map[string]string, len(enum.Value))
+	for _, v := range enum.Value {
+		override := dbEnumValue(v)
+		if override == "" {
+			override = v.GetName()
+		}
+		if prev, ok := seen[override]; ok {
+			return fmt.Errorf("%s: values %s and %s both map to dbenum string %q",
+				enum.GetName(), prev, v.GetName(), override)
+		}
+		seen[override] = v.GetName()
+	}
+	return nil
+}
+
+// generateRegister emits the init() func that registers enum's
+// EnumInfo with the dbenum runtime package, keyed by its full proto
+// name (file package + enum name).
+func (p *dbGenerator) generateRegister(file *generator.FileDescriptor, enum *descriptor.EnumDescriptorProto) {
+	fullName := fmt.Sprintf("%s.%s", file.GetPackage(), enum.GetName())
+	p.P("func init() {")
+	p.In()
+	p.P(`dbenum.RegisterEnum(protoreflect.FullName(`, fmt.Sprintf("%q", fullName), `), dbenum.EnumInfo{`)
+	p.In()
+	p.P(`Name: protoreflect.FullName(`, fmt.Sprintf("%q", fullName), `),`)
+	p.P("Names: map[int32]string{")
+	p.In()
+	for _, v := range enum.Value {
+		p.P(v.GetNumber(), ": ", fmt.Sprintf("%q", v.GetName()), ",")
+	}
+	p.Out()
+	p.P("},")
+	p.P("Strings: map[int32]string{")
+	p.In()
+	for _, v := range enum.Value {
+		override := dbEnumValue(v)
+		if override == "" {
+			override = v.GetName()
+		}
+		p.P(v.GetNumber(), ": ", fmt.Sprintf("%q", override), ",")
+	}
+	p.Out()
+	p.P("},")
+	p.Out()
+	p.P("})")
+	p.Out()
+	p.P("}")
+}
+
+// valueMetas decodes the @dbenum comment on every value of enum at
+// enumIdx within the current file, keyed by value name. A compile
+// failure (invalid JSON, or an unknown key under strict mode) is
+// reported against the offending EnumValueDescriptorProto via Fail,
+// which aborts code generation for the whole request.
+func (p *dbGenerator) valueMetas(file *generator.FileDescriptor, enumIdx int, enum *descriptor.EnumDescriptorProto, strict bool) map[string]dbenum.ValueMeta {
+	metas := make(map[string]dbenum.ValueMeta)
+	for valueIdx, v := range enum.Value {
+		path := []int32{5, int32(enumIdx), 2, int32(valueIdx)}
+		meta, ok, err := parseValueMeta(valueComment(file.FileDescriptorProto, path), strict)
+		if err != nil {
+			p.Fail(fmt.Sprintf("%s.%s: %v", enum.GetName(), v.GetName(), err))
+		}
+		if ok {
+			metas[v.GetName()] = meta
+		}
+	}
+	return metas
+}
+
+// valueComment returns the comment protoc attached to the
+// SourceCodeInfo path, preferring the trailing comment over the
+// leading one. The @dbenum marker is documented and written as a
+// same-line comment after the value it annotates
+// ("BYTES = 1; // @dbenum {...}"), which protoc records as that
+// path's trailing comment, not its leading one; generator.Generator.
+// Comments only ever returns the leading comment, so it can never see
+// a marker written that way. Walking SourceCodeInfo directly lets a
+// same-line marker be found regardless of which slot protoc filed it
+// under.
+func valueComment(file *descriptor.FileDescriptorProto, path []int32) string {
+	for _, loc := range file.GetSourceCodeInfo().GetLocation() {
+		if pathEqual(loc.Path, path) {
+			if c := loc.GetTrailingComments(); c != "" {
+				return c
+			}
+			return loc.GetLeadingComments()
+		}
+	}
+	return ""
+}
+
+func pathEqual(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *dbGenerator) generateString(enum *descriptor.EnumDescriptorProto) {
+	name := generator.CamelCase(enum.GetName())
+	p.P("func (x ", name, ") String() string {")
+	p.In()
+	p.P("switch x {")
+	for _, v := range enum.Value {
+		override := dbEnumValue(v)
+		if override == "" {
+			override = v.GetName()
+		}
+		p.P("case ", name, "_", v.GetName(), ":")
+		p.In()
+		p.P("return ", fmt.Sprintf("%q", override))
+		p.Out()
+	}
+	p.P("default:")
+	p.In()
+	p.P(`return "UNKNOWN"`)
+	p.Out()
+	p.P("}")
+	p.Out()
+	p.P("}")
+}
+
+// generateParse emits ParseEnum, IsValid, and the MarshalText/
+// UnmarshalText and MarshalJSON/UnmarshalJSON pairs, so that
+// dbenum-annotated enums are a drop-in replacement for a plain string
+// column: a value serializes as its dbenum override (or its proto
+// name, if it has none) and parses back from either form.
+func (p *dbGenerator) generateParse(enum *descriptor.EnumDescriptorProto) {
+	name := generator.CamelCase(enum.GetName())
+	p.P("func Parse", name, "(s string) (", name, ", error) {")
+	p.In()
+	p.P("switch s {")
+	for _, v := range enum.Value {
+		override := dbEnumValue(v)
+		cases := fmt.Sprintf("%q", v.GetName())
+		if override != "" && override != v.GetName() {
+			cases += ", " + fmt.Sprintf("%q", override)
+		}
+		p.P("case ", cases, ":")
+		p.In()
+		p.P("return ", name, "_", v.GetName(), ", nil")
+		p.Out()
+	}
+	p.P("default:")
+	p.In()
+	p.P("return 0, fmt.Errorf(", fmt.Sprintf("%q", "invalid "+enum.GetName()+" value %q"), ", s)")
+	p.Out()
+	p.P("}")
+	p.Out()
+	p.P("}")
+	p.P()
+	p.P("func (x ", name, ") IsValid() bool {")
+	p.In()
+	p.P("_, ok := ", name, "_name[int32(x)]")
+	p.P("return ok")
+	p.Out()
+	p.P("}")
+	p.P()
+	p.P("func (x ", name, ") MarshalText() ([]byte, error) {")
+	p.In()
+	p.P("return []byte(x.String()), nil")
+	p.Out()
+	p.P("}")
+	p.P()
+	p.P("func (x *", name, ") UnmarshalText(b []byte) error {")
+	p.In()
+	p.P("v, err := Parse", name, "(string(b))")
+	p.P("if err != nil {")
+	p.In()
+	p.P("return err")
+	p.Out()
+	p.P("}")
+	p.P("*x = v")
+	p.P("return nil")
+	p.Out()
+	p.P("}")
+	p.P()
+	p.P("func (x ", name, ") MarshalJSON() ([]byte, error) {")
+	p.In()
+	p.P("return json.Marshal(x.String())")
+	p.Out()
+	p.P("}")
+	p.P()
+	p.P("func (x *", name, ") UnmarshalJSON(b []byte) error {")
+	p.In()
+	p.P("var s string")
+	p.P("if err := json.Unmarshal(b, &s); err != nil {")
+	p.In()
+	p.P("return err")
+	p.Out()
+	p.P("}")
+	p.P("v, err := Parse", name, "(s)")
+	p.P("if err != nil {")
+	p.In()
+	p.P("return err")
+	p.Out()
+	p.P("}")
+	p.P("*x = v")
+	p.P("return nil")
+	p.Out()
+	p.P("}")
+}
+
+// generateMeta emits the Enum_dbmeta lookup table and the DBMeta()
+// accessor for every value of enum that declared @dbenum metadata.
+// Both reference dbenum.ValueMeta, not a type local to this
+// generator, since the generated file only imports the dbenum runtime
+// package and never this one.
+func (p *dbGenerator) generateMeta(enum *descriptor.EnumDescriptorProto, metas map[string]dbenum.ValueMeta) {
+	name := generator.CamelCase(enum.GetName())
+	p.P("var ", name, "_dbmeta = map[", name, "]dbenum.ValueMeta{")
+	p.In()
+	for _, v := range enum.Value {
+		meta, ok := metas[v.GetName()]
+		if !ok {
+			continue
+		}
+		p.P(name, "_", v.GetName(), ": ", formatValueMeta(meta), ",")
+	}
+	p.Out()
+	p.P("}")
+	p.P()
+	p.P("func (x ", name, ") DBMeta() dbenum.ValueMeta {")
+	p.In()
+	p.P("return ", name, "_dbmeta[x]")
+	p.Out()
+	p.P("}")
+}
+
+func formatValueMeta(m dbenum.ValueMeta) string {
+	tags := make([]string, len(m.Tags))
+	for i, t := range m.Tags {
+		tags[i] = fmt.Sprintf("%q", t)
+	}
+	return fmt.Sprintf("dbenum.ValueMeta{Display: %q, Deprecated: %t, Tags: []string{%s}}",
+		m.Display, m.Deprecated, strings.Join(tags, ", "))
+}
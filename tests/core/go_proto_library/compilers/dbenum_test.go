@@ -0,0 +1,106 @@
+/* Copyright 2019 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compilers
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	descriptor "github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+)
+
+func TestValueCommentPrefersTrailing(t *testing.T) {
+	path := []int32{5, 0, 2, 1}
+	file := &descriptor.FileDescriptorProto{
+		SourceCodeInfo: &descriptor.SourceCodeInfo{
+			Location: []*descriptor.SourceCodeInfo_Location{
+				{
+					Path:             path,
+					TrailingComments: proto.String(` @dbenum {"display":"bytes_type"}`),
+					LeadingComments:  proto.String(" unrelated leading comment"),
+				},
+			},
+		},
+	}
+	got := valueComment(file, path)
+	meta, ok, err := parseValueMeta(got, false)
+	if err != nil {
+		t.Fatalf("parseValueMeta(%q) = %v", got, err)
+	}
+	if !ok || meta.Display != "bytes_type" {
+		t.Fatalf("parseValueMeta(%q) = %+v, %v, want display bytes_type", got, meta, ok)
+	}
+}
+
+func TestValueCommentFallsBackToLeading(t *testing.T) {
+	path := []int32{5, 0, 2, 0}
+	file := &descriptor.FileDescriptorProto{
+		SourceCodeInfo: &descriptor.SourceCodeInfo{
+			Location: []*descriptor.SourceCodeInfo_Location{
+				{
+					Path:            path,
+					LeadingComments: proto.String(` @dbenum {"display":"leading_form"}`),
+				},
+			},
+		},
+	}
+	got := valueComment(file, path)
+	meta, ok, err := parseValueMeta(got, false)
+	if err != nil {
+		t.Fatalf("parseValueMeta(%q) = %v", got, err)
+	}
+	if !ok || meta.Display != "leading_form" {
+		t.Fatalf("parseValueMeta(%q) = %+v, %v, want display leading_form", got, meta, ok)
+	}
+}
+
+func dbEnumValueProto(name string, number int32, override string) *descriptor.EnumValueDescriptorProto {
+	v := &descriptor.EnumValueDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+	}
+	if override != "" {
+		v.Options = &descriptor.EnumValueOptions{}
+		if err := proto.SetExtension(v.Options, E_DBEnum, proto.String(override)); err != nil {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func TestDBEnumAmbiguity(t *testing.T) {
+	ambiguous := &descriptor.EnumDescriptorProto{
+		Name: proto.String("Enum"),
+		Value: []*descriptor.EnumValueDescriptorProto{
+			dbEnumValueProto("BYTES", 0, "dup"),
+			dbEnumValueProto("STRING", 1, "dup"),
+		},
+	}
+	if err := dbenumAmbiguity(ambiguous); err == nil {
+		t.Fatal("dbenumAmbiguity(ambiguous) = nil, want error")
+	}
+
+	unambiguous := &descriptor.EnumDescriptorProto{
+		Name: proto.String("Enum"),
+		Value: []*descriptor.EnumValueDescriptorProto{
+			dbEnumValueProto("BYTES", 0, "bytes_type"),
+			dbEnumValueProto("INT32", 1, ""),
+		},
+	}
+	if err := dbenumAmbiguity(unambiguous); err != nil {
+		t.Fatalf("dbenumAmbiguity(unambiguous) = %v, want nil", err)
+	}
+}
@@ -1,11 +1,11 @@
 package main
 
 import (
-	plugin "github.com/gogo/protobuf/protoc-gen-gogo/plugin"
 	"github.com/gogo/protobuf/vanity"
 	"github.com/gogo/protobuf/vanity/command"
 
 	dbenum "github.com/bazelbuild/rules_go/tests/core/go_proto_library/compilers"
+	"github.com/bazelbuild/rules_go/tests/core/go_proto_library/compilers/multi"
 )
 
 func main() {
@@ -19,35 +19,10 @@ func main() {
 	resp := command.Generate(req)
 	command.Write(resp)
 
-	baseFiles := req.FileToGenerate
-
-	dbenumGenerator := dbenum.NewGenerator()
-	req = onlyEnumFiles(req, baseFiles)
-	if len(req.FileToGenerate) > 0 {
-		resp = command.GeneratePlugin(req, dbenumGenerator, "_dbenum.pb.go")
-		command.Write(resp)
-	}
-}
-
-func onlyEnumFiles(
-	req *plugin.CodeGeneratorRequest, baseFiles []string,
-) *plugin.CodeGeneratorRequest {
-	// Find out files that contains enum value with dbenum extension.
-	dbEnumFiles := make(map[string]bool)
-	for _, file := range req.GetProtoFile() {
-		for _, enum := range file.EnumType {
-			if dbenum.HasDBEnum(enum.Value) {
-				dbEnumFiles[*file.Name] = true
-				break
-			}
-		}
-	}
-	enumFilesToGenerate := make([]string, 0, len(baseFiles))
-	for _, file := range baseFiles {
-		if dbEnumFiles[file] {
-			enumFilesToGenerate = append(enumFilesToGenerate, file)
-		}
-	}
-	req.FileToGenerate = enumFilesToGenerate
-	return req
+	// The dbenum extension lives on EnumValueOptions rather than
+	// EnumOptions, so it is routed with a predicate of its own instead
+	// of multi.HasEnumOption.
+	router := &multi.Router{}
+	router.Register("_dbenum.pb.go", dbenum.HasDBEnumFile, dbenum.NewGenerator())
+	command.Write(router.Run(req))
 }
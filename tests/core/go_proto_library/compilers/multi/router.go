@@ -0,0 +1,211 @@
+/* Copyright 2019 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package multi generalizes the "feed a secondary generator only the
+// files it cares about" pattern that go_proto_library's dbenum plugin
+// used to hardcode. It lets a protoc plugin register any number of
+// side-car generators, each gated by its own predicate over the
+// CodeGeneratorRequest's file descriptors, without re-reading or
+// re-filtering the request by hand for every one of them.
+package multi
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	descriptor "github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	"github.com/gogo/protobuf/protoc-gen-gogo/generator"
+	plugin_go "github.com/gogo/protobuf/protoc-gen-gogo/plugin"
+	"github.com/gogo/protobuf/vanity/command"
+)
+
+// Router multiplexes a single CodeGeneratorRequest across any number
+// of secondary generators, invoking each one with only the subset of
+// FileToGenerate whose descriptor matches that generator's predicate.
+type Router struct {
+	routes []route
+}
+
+type route struct {
+	suffix    string
+	predicate func(*descriptor.FileDescriptorProto) bool
+	gen       generator.Plugin
+}
+
+// Register adds gen to the router under suffix. suffix is both the
+// filename suffix generated files get (passed straight through to
+// command.GeneratePlugin) and the key used to pull this generator's
+// share of Run's "suffix1=k=v,suffix2=k=v" parameter string.
+func (r *Router) Register(suffix string, predicate func(*descriptor.FileDescriptorProto) bool, gen generator.Plugin) {
+	r.routes = append(r.routes, route{suffix, predicate, gen})
+}
+
+// Run reads req's proto files once, evaluates every registered
+// predicate against them, and invokes each generator with only its
+// matching files, concatenating every CodeGeneratorResponse.File into
+// a single response. A generator with no matching files is skipped.
+func (r *Router) Run(req *plugin_go.CodeGeneratorRequest) *plugin_go.CodeGeneratorResponse {
+	params := parseParameters(req.GetParameter())
+	byName := make(map[string]*descriptor.FileDescriptorProto, len(req.GetProtoFile()))
+	for _, f := range req.GetProtoFile() {
+		byName[f.GetName()] = f
+	}
+
+	resp := &plugin_go.CodeGeneratorResponse{}
+	for _, rt := range r.routes {
+		matched := matchingFiles(byName, req.FileToGenerate, rt.predicate)
+		if len(matched) == 0 {
+			continue
+		}
+		sub := *req
+		sub.FileToGenerate = matched
+		if p, ok := params[rt.suffix]; ok {
+			sub.Parameter = proto.String(p)
+		}
+		out := command.GeneratePlugin(&sub, rt.gen, rt.suffix)
+		resp.File = append(resp.File, out.File...)
+	}
+	return resp
+}
+
+func matchingFiles(
+	byName map[string]*descriptor.FileDescriptorProto,
+	baseFiles []string,
+	predicate func(*descriptor.FileDescriptorProto) bool,
+) []string {
+	var matched []string
+	for _, name := range baseFiles {
+		if f, ok := byName[name]; ok && predicate(f) {
+			matched = append(matched, name)
+		}
+	}
+	return matched
+}
+
+// parseParameters splits a plugin "parameter" string using the
+// "suffix1=k=v,suffix2=k=v" convention into one parameter string per
+// generator suffix: each top-level comma-separated entry is routed by
+// the text before its first "=", with the remainder handed to that
+// generator verbatim. A suffix that appears more than once gets its
+// segments joined with ",".
+func parseParameters(raw string) map[string]string {
+	out := make(map[string]string)
+	if raw == "" {
+		return out
+	}
+	for _, entry := range strings.Split(raw, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		suffix, kv := parts[0], parts[1]
+		if existing, ok := out[suffix]; ok {
+			out[suffix] = existing + "," + kv
+		} else {
+			out[suffix] = kv
+		}
+	}
+	return out
+}
+
+// HasEnumOption returns a predicate matching any file that declares an
+// enum whose EnumOptions carries the extension numbered extNum.
+func HasEnumOption(extNum int32) func(*descriptor.FileDescriptorProto) bool {
+	return func(file *descriptor.FileDescriptorProto) bool {
+		for _, enum := range file.EnumType {
+			if enum.GetOptions() != nil && hasWireField(enum.GetOptions(), extNum) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasMessageOption returns a predicate matching any file that declares
+// a message whose MessageOptions carries the extension numbered
+// extNum.
+func HasMessageOption(extNum int32) func(*descriptor.FileDescriptorProto) bool {
+	return func(file *descriptor.FileDescriptorProto) bool {
+		for _, msg := range file.MessageType {
+			if msg.GetOptions() != nil && hasWireField(msg.GetOptions(), extNum) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasServiceOption returns a predicate matching any file that declares
+// a service whose ServiceOptions carries the extension numbered
+// extNum.
+func HasServiceOption(extNum int32) func(*descriptor.FileDescriptorProto) bool {
+	return func(file *descriptor.FileDescriptorProto) bool {
+		for _, svc := range file.Service {
+			if svc.GetOptions() != nil && hasWireField(svc.GetOptions(), extNum) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// hasWireField reports whether msg's encoded form contains a field
+// numbered fieldNum, regardless of its wire type. It lets the
+// HasXOption helpers match an extension by field number alone,
+// without requiring its concrete Go type to be registered.
+func hasWireField(msg proto.Message, fieldNum int32) bool {
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		return false
+	}
+	for len(raw) > 0 {
+		tag, n := binary.Uvarint(raw)
+		if n <= 0 {
+			return false
+		}
+		raw = raw[n:]
+		if int32(tag>>3) == fieldNum {
+			return true
+		}
+		switch tag & 7 {
+		case 0: // varint
+			_, n := binary.Uvarint(raw)
+			if n <= 0 {
+				return false
+			}
+			raw = raw[n:]
+		case 1: // 64-bit
+			if len(raw) < 8 {
+				return false
+			}
+			raw = raw[8:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(raw)
+			if n <= 0 || len(raw) < n+int(l) {
+				return false
+			}
+			raw = raw[n+int(l):]
+		case 5: // 32-bit
+			if len(raw) < 4 {
+				return false
+			}
+			raw = raw[4:]
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+/* Copyright 2019 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multi
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	descriptor "github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+)
+
+const testExtNum = 70001
+
+var enumExt = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.EnumOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         testExtNum,
+	Name:          "multi.test.enum_ext",
+	Tag:           "bytes,70001,opt,name=enum_ext",
+}
+
+var messageExt = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MessageOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         testExtNum,
+	Name:          "multi.test.message_ext",
+	Tag:           "bytes,70001,opt,name=message_ext",
+}
+
+var serviceExt = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.ServiceOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         testExtNum,
+	Name:          "multi.test.service_ext",
+	Tag:           "bytes,70001,opt,name=service_ext",
+}
+
+func TestHasEnumOption(t *testing.T) {
+	withExt := &descriptor.EnumOptions{}
+	if err := proto.SetExtension(withExt, enumExt, proto.String("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	match := HasEnumOption(testExtNum)
+	for _, tc := range []struct {
+		name string
+		file *descriptor.FileDescriptorProto
+		want bool
+	}{
+		{"no enums", &descriptor.FileDescriptorProto{}, false},
+		{
+			"enum without options",
+			&descriptor.FileDescriptorProto{
+				EnumType: []*descriptor.EnumDescriptorProto{{Name: proto.String("E")}},
+			},
+			false,
+		},
+		{
+			"enum with unrelated options",
+			&descriptor.FileDescriptorProto{
+				EnumType: []*descriptor.EnumDescriptorProto{{
+					Name:    proto.String("E"),
+					Options: &descriptor.EnumOptions{},
+				}},
+			},
+			false,
+		},
+		{
+			"enum with matching extension",
+			&descriptor.FileDescriptorProto{
+				EnumType: []*descriptor.EnumDescriptorProto{{
+					Name:    proto.String("E"),
+					Options: withExt,
+				}},
+			},
+			true,
+		},
+	} {
+		if got := match(tc.file); got != tc.want {
+			t.Errorf("%s: HasEnumOption(%d)(file) = %v, want %v", tc.name, testExtNum, got, tc.want)
+		}
+	}
+}
+
+func TestHasMessageOption(t *testing.T) {
+	withExt := &descriptor.MessageOptions{}
+	if err := proto.SetExtension(withExt, messageExt, proto.String("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	match := HasMessageOption(testExtNum)
+	for _, tc := range []struct {
+		name string
+		file *descriptor.FileDescriptorProto
+		want bool
+	}{
+		{"no messages", &descriptor.FileDescriptorProto{}, false},
+		{
+			"message without matching extension",
+			&descriptor.FileDescriptorProto{
+				MessageType: []*descriptor.DescriptorProto{{
+					Name:    proto.String("M"),
+					Options: &descriptor.MessageOptions{},
+				}},
+			},
+			false,
+		},
+		{
+			"message with matching extension",
+			&descriptor.FileDescriptorProto{
+				MessageType: []*descriptor.DescriptorProto{{
+					Name:    proto.String("M"),
+					Options: withExt,
+				}},
+			},
+			true,
+		},
+	} {
+		if got := match(tc.file); got != tc.want {
+			t.Errorf("%s: HasMessageOption(%d)(file) = %v, want %v", tc.name, testExtNum, got, tc.want)
+		}
+	}
+}
+
+func TestHasServiceOption(t *testing.T) {
+	withExt := &descriptor.ServiceOptions{}
+	if err := proto.SetExtension(withExt, serviceExt, proto.String("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	match := HasServiceOption(testExtNum)
+	for _, tc := range []struct {
+		name string
+		file *descriptor.FileDescriptorProto
+		want bool
+	}{
+		{"no services", &descriptor.FileDescriptorProto{}, false},
+		{
+			"service without matching extension",
+			&descriptor.FileDescriptorProto{
+				Service: []*descriptor.ServiceDescriptorProto{{
+					Name:    proto.String("S"),
+					Options: &descriptor.ServiceOptions{},
+				}},
+			},
+			false,
+		},
+		{
+			"service with matching extension",
+			&descriptor.FileDescriptorProto{
+				Service: []*descriptor.ServiceDescriptorProto{{
+					Name:    proto.String("S"),
+					Options: withExt,
+				}},
+			},
+			true,
+		},
+	} {
+		if got := match(tc.file); got != tc.want {
+			t.Errorf("%s: HasServiceOption(%d)(file) = %v, want %v", tc.name, testExtNum, got, tc.want)
+		}
+	}
+}
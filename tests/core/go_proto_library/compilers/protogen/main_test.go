@@ -0,0 +1,89 @@
+/* Copyright 2019 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// fixtureRequest builds a minimal CodeGeneratorRequest for a single
+// file declaring the same Enum{BYTES, INT32} shape the gogo generator
+// is exercised against in compiler_multi_suffix_test.go, so the two
+// generators can be asserted against the same override behavior.
+func fixtureRequest() *pluginpb.CodeGeneratorRequest {
+	byteOpts := &descriptorpb.EnumValueOptions{}
+	if err := proto.SetExtension(byteOpts, E_Value, "bytes_type"); err != nil {
+		panic(err)
+	}
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"dbenum_fixture.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("dbenum_fixture.proto"),
+				Package: proto.String("dbenumtest"),
+				Syntax:  proto.String("proto3"),
+				Options: &descriptorpb.FileOptions{GoPackage: proto.String("dbenumtest")},
+				EnumType: []*descriptorpb.EnumDescriptorProto{
+					{
+						Name: proto.String("Enum"),
+						Value: []*descriptorpb.EnumValueDescriptorProto{
+							{Name: proto.String("BYTES"), Number: proto.Int32(0), Options: byteOpts},
+							{Name: proto.String("INT32"), Number: proto.Int32(1)},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestGenerateFile wires protoc-gen-dbenum-protogen into a fixture
+// CodeGeneratorRequest instead of a BUILD-level protoc invocation, so
+// this generator's output is actually exercised: it asserts the
+// generated String() override returns the dbenum override for
+// BYTES and falls back to the proto name for the unannotated INT32,
+// the same cases compiler_multi_suffix_test.go checks against the
+// gogo-based generator's output.
+func TestGenerateFile(t *testing.T) {
+	gen, err := protogen.Options{}.New(fixtureRequest())
+	if err != nil {
+		t.Fatalf("protogen.Options{}.New() = %v", err)
+	}
+	for _, file := range gen.Files {
+		if !file.Generate || !fileHasDBEnum(file) {
+			continue
+		}
+		generateFile(gen, file)
+	}
+
+	resp := gen.Response()
+	if len(resp.File) != 1 {
+		t.Fatalf("len(resp.File) = %d, want 1", len(resp.File))
+	}
+	content := resp.File[0].GetContent()
+	if !strings.Contains(content, `return "bytes_type"`) {
+		t.Fatalf("generated file missing BYTES override:\n%s", content)
+	}
+	if !strings.Contains(content, `return "INT32"`) {
+		t.Fatalf("generated file missing INT32 fallback:\n%s", content)
+	}
+}
@@ -0,0 +1,111 @@
+/* Copyright 2019 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dbenum is the runtime counterpart to the
+// tests/core/go_proto_library/compilers dbenum code generator. Every
+// generated "_dbenum.pb.go" file registers its enum here from an
+// init() func, so that applications can enumerate every
+// dbenum-annotated type at runtime (for schema migrations, admin UIs,
+// etc.) without re-reading descriptors, the same way
+// google.golang.org/protobuf/reflect/protoregistry.Files lets callers
+// enumerate compiled proto files.
+package dbenum
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// EnumInfo describes one dbenum-annotated enum at runtime.
+type EnumInfo struct {
+	// Name is the enum's full proto name, e.g. "my.pkg.Enum".
+	Name protoreflect.FullName
+	// Names maps each value's number to its proto name.
+	Names map[int32]string
+	// Strings maps each value's number to its dbenum override string,
+	// or its proto name when no override was declared.
+	Strings map[int32]string
+}
+
+// ParseDBString looks up the value number whose dbenum string form
+// equals s.
+func (e EnumInfo) ParseDBString(s string) (int32, bool) {
+	for num, str := range e.Strings {
+		if str == s {
+			return num, true
+		}
+	}
+	return 0, false
+}
+
+// Files is a thread-safe registry of EnumInfo, keyed by proto package
+// and then by the enum's full name within it.
+type Files struct {
+	mu    sync.RWMutex
+	byPkg map[protoreflect.FullName]map[protoreflect.FullName]EnumInfo
+}
+
+// GlobalFiles is the registry every generated dbenum init() registers
+// into, and the one FindEnumByName and RangeEnums read from.
+var GlobalFiles = &Files{byPkg: make(map[protoreflect.FullName]map[protoreflect.FullName]EnumInfo)}
+
+// RegisterEnum adds info under fullName. A second registration under
+// the same name is a bug in the generator or its inputs, not a
+// runtime condition callers should recover from, so it panics the way
+// protoregistry.GlobalFiles does on a duplicate file.
+func RegisterEnum(fullName protoreflect.FullName, info EnumInfo) {
+	f := GlobalFiles
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pkgEnums, ok := f.byPkg[fullName.Parent()]
+	if !ok {
+		pkgEnums = make(map[protoreflect.FullName]EnumInfo)
+		f.byPkg[fullName.Parent()] = pkgEnums
+	}
+	if _, dup := pkgEnums[fullName]; dup {
+		panic(fmt.Sprintf("dbenum: enum %q already registered", fullName))
+	}
+	pkgEnums[fullName] = info
+}
+
+// FindEnumByName returns the EnumInfo registered under fullName.
+func FindEnumByName(fullName protoreflect.FullName) (EnumInfo, bool) {
+	f := GlobalFiles
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	pkgEnums, ok := f.byPkg[fullName.Parent()]
+	if !ok {
+		return EnumInfo{}, false
+	}
+	info, ok := pkgEnums[fullName]
+	return info, ok
+}
+
+// RangeEnums calls f for every registered EnumInfo until f returns
+// false.
+func RangeEnums(f func(EnumInfo) bool) {
+	reg := GlobalFiles
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, pkgEnums := range reg.byPkg {
+		for _, info := range pkgEnums {
+			if !f(info) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/* Copyright 2019 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dbenum
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestRegisterEnumAndFindEnumByName(t *testing.T) {
+	fullName := protoreflect.FullName("dbenum_test.registry.LookupEnum")
+	info := EnumInfo{
+		Name:    fullName,
+		Names:   map[int32]string{0: "BYTES", 1: "INT32"},
+		Strings: map[int32]string{0: "bytes_type", 1: "INT32"},
+	}
+	RegisterEnum(fullName, info)
+
+	got, ok := FindEnumByName(fullName)
+	if !ok {
+		t.Fatalf("FindEnumByName(%q) = _, false, want true", fullName)
+	}
+	if got.Name != fullName {
+		t.Fatalf("FindEnumByName(%q).Name = %q, want %q", fullName, got.Name, fullName)
+	}
+
+	if _, ok := FindEnumByName("dbenum_test.registry.Missing"); ok {
+		t.Fatalf("FindEnumByName(%q) = _, true, want false", "dbenum_test.registry.Missing")
+	}
+}
+
+func TestGlobalFilesRegisterAndLookup(t *testing.T) {
+	fullName := protoreflect.FullName("dbenum_test.registry.RoundTripEnum")
+	info := EnumInfo{
+		Name:    fullName,
+		Names:   map[int32]string{0: "A", 1: "B"},
+		Strings: map[int32]string{0: "a_str", 1: "B"},
+	}
+	RegisterEnum(fullName, info)
+
+	got, ok := FindEnumByName(fullName)
+	if !ok {
+		t.Fatalf("FindEnumByName(%q) = _, false, want true", fullName)
+	}
+	if got.Name != fullName {
+		t.Fatalf("FindEnumByName(%q).Name = %q, want %q", fullName, got.Name, fullName)
+	}
+
+	num, ok := got.ParseDBString("a_str")
+	if !ok || num != 0 {
+		t.Fatalf("ParseDBString(%q) = %d, %v, want 0, true", "a_str", num, ok)
+	}
+	if _, ok := got.ParseDBString("nope"); ok {
+		t.Fatalf("ParseDBString(%q) = _, true, want false", "nope")
+	}
+
+	var seen bool
+	RangeEnums(func(e EnumInfo) bool {
+		if e.Name == fullName {
+			seen = true
+		}
+		return true
+	})
+	if !seen {
+		t.Fatalf("RangeEnums did not yield %q", fullName)
+	}
+}
+
+func TestRegisterEnumDuplicatePanics(t *testing.T) {
+	fullName := protoreflect.FullName("dbenum_test.registry.DupEnum")
+	RegisterEnum(fullName, EnumInfo{Name: fullName})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("RegisterEnum with a duplicate name did not panic")
+		}
+	}()
+	RegisterEnum(fullName, EnumInfo{Name: fullName})
+}
@@ -0,0 +1,28 @@
+/* Copyright 2019 The Bazel Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dbenum
+
+// ValueMeta is the structured metadata an enum value can declare via
+// its @dbenum trailing comment. The compilers generator decodes it
+// from JSON at generation time and embeds it in the generated
+// Enum_dbmeta map, which is why the type lives here rather than in
+// the generator package: generated code refers to it as
+// dbenum.ValueMeta.
+type ValueMeta struct {
+	Display    string   `json:"display"`
+	Deprecated bool     `json:"deprecated"`
+	Tags       []string `json:"tags"`
+}